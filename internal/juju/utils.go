@@ -1,15 +1,22 @@
 package juju
 
 import (
+	"context"
+	"encoding/json"
 	"errors"
 	"fmt"
+	"os"
 	"os/exec"
+	"path/filepath"
+	"runtime"
+	"sort"
 	"strings"
 	"sync"
-
-	"encoding/json"
+	"time"
 
 	"github.com/rs/zerolog/log"
+	"golang.org/x/oauth2/clientcredentials"
+	"gopkg.in/yaml.v2"
 )
 
 // controllerConfig is a representation of the output
@@ -40,72 +47,647 @@ type controllerConfig struct {
 	} `json:"account"`
 }
 
-// localProviderConfig is populated once and queried later
-// to avoid multiple juju CLI executions
-var localProviderConfig map[string]string
+// clientStoreControllers is the subset of Juju's controllers.yaml, as
+// written by the juju CLI into the XDG client store, that we need.
+type clientStoreControllers struct {
+	Controllers       map[string]clientStoreController `yaml:"controllers"`
+	CurrentController string                           `yaml:"current-controller"`
+}
+
+// clientStoreController is a single entry under controllers.yaml's
+// top-level "controllers" map.
+type clientStoreController struct {
+	APIEndpoints []string `yaml:"api-endpoints"`
+	CACert       string   `yaml:"ca-cert"`
+}
+
+// clientStoreAccounts is the subset of Juju's accounts.yaml that we need.
+type clientStoreAccounts struct {
+	Controllers map[string]struct {
+		User     string `yaml:"user"`
+		Password string `yaml:"password"`
+	} `yaml:"controllers"`
+}
+
+// clientStoreModels is the subset of Juju's models.yaml that we need.
+type clientStoreModels struct {
+	Controllers map[string]struct {
+		Models map[string]struct {
+			UUID string `yaml:"uuid"`
+		} `yaml:"models"`
+		CurrentModel string `yaml:"current-model"`
+	} `yaml:"controllers"`
+}
 
-// singleQuery will be used to limit the number of CLI queries to ONE
-var singleQuery sync.Once
+// controllerConfigErrorKind distinguishes the different ways resolving a
+// controller configuration can fail, so callers can react accordingly
+// instead of matching on error strings.
+type controllerConfigErrorKind int
 
-// GetLocalControllerConfig runs the locally installed juju command,
-// if available, to get the current controller configuration.
-func GetLocalControllerConfig() (map[string]string, error) {
-	// populate the controller config information only once
-	singleQuery.Do(populateControllerConfig)
+const (
+	// ErrStoreUnavailable means the local Juju client store couldn't be
+	// read at all (e.g. no controllers.yaml), so the CLI should be tried.
+	ErrStoreUnavailable controllerConfigErrorKind = iota
+	// ErrCLINotInstalled means the juju binary isn't on PATH.
+	ErrCLINotInstalled
+	// ErrNoControllers means no controller is registered, or the
+	// requested controller, its account, or the requested (or current)
+	// model doesn't exist.
+	ErrNoControllers
+	// ErrUnmarshalFailed means the client store or CLI output was
+	// malformed.
+	ErrUnmarshalFailed
+)
 
-	// if empty something went wrong
-	if localProviderConfig == nil {
-		return nil, errors.New("the Juju CLI could not be accessed")
+// controllerConfigError is returned by populateControllerConfig (and the
+// loaders it calls) so callers can distinguish "juju not installed" from
+// "no controllers registered" from "unmarshal failed".
+type controllerConfigError struct {
+	Kind controllerConfigErrorKind
+	Msg  string
+	Err  error
+}
+
+func (e *controllerConfigError) Error() string {
+	if e.Err != nil {
+		return fmt.Sprintf("%s: %v", e.Msg, e.Err)
 	}
+	return e.Msg
+}
 
-	return localProviderConfig, nil
+func (e *controllerConfigError) Unwrap() error {
+	return e.Err
 }
 
-// populateControllerConfig executes the local juju CLI command
-// to obtain the current controller configuration
-func populateControllerConfig() {
-	// get the value from the juju provider
-	cmd := exec.Command("juju", "show-controller", "--show-password", "--format=json")
+// controllerConfigTTL bounds how long a resolved controller
+// configuration is reused before it is automatically re-fetched.
+const controllerConfigTTL = 10 * time.Minute
 
-	cmdData, err := cmd.Output()
+// controllerConfigCache holds the result of the last successful (or
+// failed) controller config lookup, guarded by a RWMutex so concurrent
+// resource reads don't race on refresh.
+type controllerConfigCache struct {
+	mu        sync.RWMutex
+	config    map[string]string
+	err       error
+	key       string
+	fetchedAt time.Time
+}
+
+var cache controllerConfigCache
+
+// GetLocalControllerConfig gets the configuration for controllerName
+// (and, optionally, modelName), read from the local Juju client store if
+// available, falling back to the juju CLI. An empty controllerName
+// honours the JUJU_CONTROLLER environment variable and, failing that,
+// the current controller as set by `juju switch`.
+//
+// The result is cached for controllerConfigTTL. Use
+// RefreshLocalControllerConfig to force an earlier re-fetch.
+func GetLocalControllerConfig(controllerName string, modelName string) (map[string]string, error) {
+	return getControllerConfig(controllerName, modelName, "")
+}
+
+// GetControllerConfig is GetLocalControllerConfig, plus an optional
+// serverFile: the path to (or inline contents of) a Juju "server file",
+// which takes precedence over both the client store and the CLI probe
+// when set.
+func GetControllerConfig(controllerName string, modelName string, serverFile string) (map[string]string, error) {
+	return getControllerConfig(controllerName, modelName, serverFile)
+}
+
+func getControllerConfig(controllerName string, modelName string, serverFile string) (map[string]string, error) {
+	key := controllerName + "/" + modelName + "/" + serverFile
+
+	cache.mu.RLock()
+	if cache.config != nil && cache.key == key && time.Since(cache.fetchedAt) < controllerConfigTTL {
+		defer cache.mu.RUnlock()
+		return copyConfig(cache.config), nil
+	}
+	cache.mu.RUnlock()
+
+	return refreshControllerConfig(key, controllerName, modelName, serverFile)
+}
+
+// copyConfig returns a shallow copy of config, so a caller that mutates
+// the map it gets back (e.g. GetJWTControllerConfig swapping credentials)
+// can't corrupt the shared cache entry.
+func copyConfig(config map[string]string) map[string]string {
+	result := make(map[string]string, len(config))
+	for k, v := range config {
+		result[k] = v
+	}
+	return result
+}
+
+// RefreshLocalControllerConfig discards any cached controller
+// configuration, forcing the next call to GetLocalControllerConfig to
+// re-read it from the client store (or CLI). Useful for long-running
+// processes, such as Terraform Cloud/Enterprise workers, and for tests.
+func RefreshLocalControllerConfig() {
+	cache.mu.Lock()
+	defer cache.mu.Unlock()
+
+	cache.config = nil
+	cache.err = nil
+	cache.key = ""
+	cache.fetchedAt = time.Time{}
+}
+
+// refreshControllerConfig re-populates the cache for key and returns the
+// result, whether it succeeded or failed.
+func refreshControllerConfig(key string, controllerName string, modelName string, serverFile string) (map[string]string, error) {
+	cache.mu.Lock()
+	defer cache.mu.Unlock()
+
+	config, err := populateControllerConfig(resolveControllerName(controllerName), modelName, serverFile)
+
+	cache.config = config
+	cache.err = err
+	cache.key = key
+	cache.fetchedAt = time.Now()
+
+	if config == nil {
+		return nil, err
+	}
+	return copyConfig(config), err
+}
+
+// resolveControllerName honours an explicit controller name, falling
+// back to the JUJU_CONTROLLER environment variable.
+func resolveControllerName(controllerName string) string {
+	if controllerName != "" {
+		return controllerName
+	}
+	return os.Getenv("JUJU_CONTROLLER")
+}
+
+// populateControllerConfig reads the configuration for controllerName
+// (or, if empty, the current controller) from the local Juju client
+// store (controllers.yaml/accounts.yaml/models.yaml under the Juju data
+// directory), which is far cheaper than forking the juju CLI. If the
+// store can't be read, it falls back to the CLI. serverFile, if set,
+// takes precedence over both: it's an explicit credentials artifact
+// supplied by the caller, so there's nothing to probe.
+func populateControllerConfig(controllerName string, modelName string, serverFile string) (map[string]string, error) {
+	if serverFile != "" {
+		config, err := GetControllerConfigFromServerFile(serverFile)
+		if err != nil {
+			log.Error().Err(err).Msg("error reading controller configuration from the server file")
+			return nil, err
+		}
+		log.Debug().Str("localProviderConfig", fmt.Sprintf("%#v", config)).Msg("local provider config was set from the server file")
+		return config, nil
+	}
+
+	dataDir := jujuDataDir()
+	log.Debug().Str("jujuDataDir", dataDir).Msg("looking up controller configuration in the Juju client store")
+
+	config, err := controllerConfigFromClientStore(dataDir, controllerName, modelName)
+	if err == nil {
+		log.Debug().Str("localProviderConfig", fmt.Sprintf("%#v", config)).Msg("local provider config was set")
+		return config, nil
+	}
+
+	var cfgErr *controllerConfigError
+	if !errors.As(err, &cfgErr) || cfgErr.Kind != ErrStoreUnavailable {
+		log.Error().Err(err).Msg("error reading controller configuration from the Juju client store")
+		return nil, err
+	}
+
+	log.Warn().Err(err).Msg("could not read the Juju client store, falling back to the juju CLI")
+
+	config, err = controllerConfigFromCLI(dataDir, controllerName, modelName)
 	if err != nil {
 		log.Error().Err(err).Msg("error invoking juju CLI")
-		return
+		return nil, err
+	}
+
+	log.Debug().Str("localProviderConfig", fmt.Sprintf("%#v", config)).Msg("local provider config was set")
+	return config, nil
+}
+
+// jujuDataDir returns the directory holding Juju's client store,
+// honouring the JUJU_DATA environment variable and otherwise falling
+// back to the per-OS default used by the juju CLI.
+func jujuDataDir() string {
+	if dir := os.Getenv("JUJU_DATA"); dir != "" {
+		return dir
+	}
+
+	home, _ := os.UserHomeDir()
+	switch runtime.GOOS {
+	case "windows":
+		return filepath.Join(os.Getenv("APPDATA"), "Juju")
+	case "darwin":
+		return filepath.Join(home, "Library", "Application Support", "juju")
+	default:
+		return filepath.Join(home, ".local", "share", "juju")
+	}
+}
+
+// controllerConfigFromClientStore parses controllers.yaml, accounts.yaml
+// and models.yaml from the given Juju client store directory and returns
+// the configuration for controllerName. An empty controllerName resolves
+// to the current controller, i.e. the one `juju switch` would target. An
+// empty modelName is likewise resolved to the controller's current model,
+// if one is set.
+func controllerConfigFromClientStore(dataDir string, controllerName string, modelName string) (map[string]string, error) {
+	controllersData, err := os.ReadFile(filepath.Join(dataDir, "controllers.yaml"))
+	if err != nil {
+		return nil, &controllerConfigError{Kind: ErrStoreUnavailable, Msg: "error reading controllers.yaml", Err: err}
+	}
+
+	var controllers clientStoreControllers
+	if err := yaml.Unmarshal(controllersData, &controllers); err != nil {
+		return nil, &controllerConfigError{Kind: ErrUnmarshalFailed, Msg: "error unmarshalling controllers.yaml", Err: err}
+	}
+
+	name := controllerName
+	if name == "" {
+		name = controllers.CurrentController
+	}
+	if name == "" {
+		return nil, &controllerConfigError{Kind: ErrNoControllers, Msg: "no current controller set in controllers.yaml"}
+	}
+
+	controller, ok := controllers.Controllers[name]
+	if !ok {
+		return nil, &controllerConfigError{
+			Kind: ErrNoControllers,
+			Msg:  fmt.Sprintf("controller %q not found in controllers.yaml (available: %s)", name, availableKeys(controllers.Controllers)),
+		}
+	}
+
+	accountsData, err := os.ReadFile(filepath.Join(dataDir, "accounts.yaml"))
+	if err != nil {
+		return nil, &controllerConfigError{Kind: ErrStoreUnavailable, Msg: "error reading accounts.yaml", Err: err}
+	}
+
+	var accounts clientStoreAccounts
+	if err := yaml.Unmarshal(accountsData, &accounts); err != nil {
+		return nil, &controllerConfigError{Kind: ErrUnmarshalFailed, Msg: "error unmarshalling accounts.yaml", Err: err}
+	}
+
+	account, ok := accounts.Controllers[name]
+	if !ok {
+		return nil, &controllerConfigError{Kind: ErrNoControllers, Msg: fmt.Sprintf("no account found for controller %q in accounts.yaml", name)}
+	}
+
+	config := map[string]string{
+		"JUJU_CONTROLLER_ADDRESSES": strings.Join(controller.APIEndpoints, ","),
+		"JUJU_CA_CERT":              controller.CACert,
+		"JUJU_USERNAME":             account.User,
+		"JUJU_PASSWORD":             account.Password,
+	}
+
+	modelUUID, err := modelUUIDFromClientStore(dataDir, name, modelName)
+	if err != nil {
+		return nil, err
+	}
+	if modelUUID != "" {
+		config["JUJU_MODEL_UUID"] = modelUUID
+	}
+
+	return config, nil
+}
+
+// modelUUIDFromClientStore resolves modelName (or, if empty, the
+// controller's current model) against models.yaml. Model selection is
+// optional, so a missing models.yaml, missing current model, or missing
+// models.yaml entry for controllerName is not an error when modelName is
+// empty. But an explicitly requested modelName that can't be resolved,
+// for any of those reasons, is a clear error, same as an unknown
+// controller name.
+func modelUUIDFromClientStore(dataDir string, controllerName string, modelName string) (string, error) {
+	modelsData, err := os.ReadFile(filepath.Join(dataDir, "models.yaml"))
+	if err != nil {
+		if modelName != "" {
+			return "", &controllerConfigError{
+				Kind: ErrNoControllers,
+				Msg:  fmt.Sprintf("model %q requested but models.yaml could not be read", modelName),
+				Err:  err,
+			}
+		}
+		return "", nil
+	}
+
+	var models clientStoreModels
+	if err := yaml.Unmarshal(modelsData, &models); err != nil {
+		return "", &controllerConfigError{Kind: ErrUnmarshalFailed, Msg: "error unmarshalling models.yaml", Err: err}
+	}
+
+	controllerModels, ok := models.Controllers[controllerName]
+	if !ok {
+		if modelName != "" {
+			return "", &controllerConfigError{
+				Kind: ErrNoControllers,
+				Msg:  fmt.Sprintf("model %q requested but no models found in models.yaml for controller %q", modelName, controllerName),
+			}
+		}
+		return "", nil
+	}
+
+	name := modelName
+	if name == "" {
+		name = controllerModels.CurrentModel
+	}
+	if name == "" {
+		return "", nil
+	}
+
+	model, ok := controllerModels.Models[name]
+	if !ok {
+		return "", &controllerConfigError{
+			Kind: ErrNoControllers,
+			Msg:  fmt.Sprintf("model %q not found in models.yaml for controller %q (available: %s)", name, controllerName, availableModelKeys(controllerModels.Models)),
+		}
+	}
+
+	return model.UUID, nil
+}
+
+// availableModelKeys returns the sorted keys of a models.yaml-shaped
+// per-controller model map, for use in "not found" error messages.
+func availableModelKeys(models map[string]struct {
+	UUID string `yaml:"uuid"`
+}) string {
+	names := make([]string, 0, len(models))
+	for name := range models {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return strings.Join(names, ", ")
+}
+
+// availableKeys returns the sorted keys of a controllers.yaml-shaped map,
+// for use in "not found" error messages.
+func availableKeys(controllers map[string]clientStoreController) string {
+	names := make([]string, 0, len(controllers))
+	for name := range controllers {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return strings.Join(names, ", ")
+}
+
+// currentControllerFromClientStore reads just the current-controller
+// selection out of controllers.yaml, ignoring any error. It lets the CLI
+// fallback resolve the same controller the client-store path would have
+// used, even though the overall client-store read failed (e.g. because
+// accounts.yaml is missing), instead of picking an arbitrary map entry.
+func currentControllerFromClientStore(dataDir string) string {
+	data, err := os.ReadFile(filepath.Join(dataDir, "controllers.yaml"))
+	if err != nil {
+		return ""
+	}
+
+	var controllers clientStoreControllers
+	if err := yaml.Unmarshal(data, &controllers); err != nil {
+		return ""
+	}
+
+	return controllers.CurrentController
+}
+
+// controllerConfigFromCLI executes the local juju CLI command to obtain
+// the configuration for controllerName (or, if empty, the current
+// controller, resolved from the client store's controllers.yaml when
+// possible). It is used as a fallback when the Juju client store can't
+// be read, i.e. when controllers.yaml or accounts.yaml can't be read
+// from the data directory.
+func controllerConfigFromCLI(dataDir string, controllerName string, modelName string) (map[string]string, error) {
+	name := controllerName
+	if name == "" {
+		name = currentControllerFromClientStore(dataDir)
+	}
+
+	args := []string{"show-controller", "--show-password", "--format=json"}
+	if name != "" {
+		args = append(args, name)
+	}
+
+	cmd := exec.Command("juju", args...)
+
+	cmdData, err := cmd.Output()
+	if err != nil {
+		if errors.Is(err, exec.ErrNotFound) {
+			return nil, &controllerConfigError{Kind: ErrCLINotInstalled, Msg: "the juju CLI is not installed", Err: err}
+		}
+		return nil, fmt.Errorf("error invoking juju CLI: %w", err)
 	}
 
 	// given that the CLI output is a map containing arbitrary keys
 	// (controllers) and fixed json structures, we have to do some
 	// workaround to populate the struct
 	var cliOutput interface{}
-	err = json.Unmarshal(cmdData, &cliOutput)
+	if err := json.Unmarshal(cmdData, &cliOutput); err != nil {
+		return nil, &controllerConfigError{Kind: ErrUnmarshalFailed, Msg: "error unmarshalling Juju CLI output", Err: err}
+	}
+
+	cliControllers, ok := cliOutput.(map[string]interface{})
+	if !ok {
+		return nil, &controllerConfigError{Kind: ErrUnmarshalFailed, Msg: "Juju CLI output was not a JSON object"}
+	}
+	if len(cliControllers) == 0 {
+		return nil, &controllerConfigError{Kind: ErrNoControllers, Msg: "no controllers registered"}
+	}
+
+	cliControllerNames := func() []string {
+		names := make([]string, 0, len(cliControllers))
+		for n := range cliControllers {
+			names = append(names, n)
+		}
+		sort.Strings(names)
+		return names
+	}
+
+	var selected interface{}
+	switch {
+	case name != "":
+		var ok bool
+		selected, ok = cliControllers[name]
+		if !ok {
+			return nil, &controllerConfigError{
+				Kind: ErrNoControllers,
+				Msg:  fmt.Sprintf("controller %q not found (available: %s)", name, strings.Join(cliControllerNames(), ", ")),
+			}
+		}
+	case len(cliControllers) == 1:
+		for _, v := range cliControllers {
+			selected = v
+		}
+	default:
+		// no controller requested, and the client store doesn't know of a
+		// current one either: with more than one registered controller
+		// there's no deterministic choice to make.
+		return nil, &controllerConfigError{
+			Kind: ErrNoControllers,
+			Msg:  fmt.Sprintf("no controller selected and more than one is registered (available: %s)", strings.Join(cliControllerNames(), ", ")),
+		}
+	}
+
+	marshalled, err := json.Marshal(selected)
 	if err != nil {
-		log.Error().Err(err).Msg("error unmarshalling Juju CLI output")
-		return
+		return nil, fmt.Errorf("error marshalling provider config: %w", err)
+	}
+
+	// now we have a controllerConfig type
+	config := controllerConfig{}
+	if err := json.Unmarshal(marshalled, &config); err != nil {
+		return nil, &controllerConfigError{Kind: ErrUnmarshalFailed, Msg: "error unmarshalling provider configuration from Juju CLI", Err: err}
+	}
+
+	result := map[string]string{
+		"JUJU_CONTROLLER_ADDRESSES": strings.Join(config.ProviderDetails.ApiEndpoints, ","),
+		"JUJU_CA_CERT":              config.ProviderDetails.CACert,
+		"JUJU_USERNAME":             config.Account.User,
+		"JUJU_PASSWORD":             config.Account.Password,
+	}
+
+	modelSelector := modelName
+	if modelSelector == "" {
+		modelSelector = config.CurrentModel
+	}
+	if model, ok := config.Models[modelSelector]; ok {
+		result["JUJU_MODEL_UUID"] = model.UUID
+	} else if modelName != "" {
+		modelNames := make([]string, 0, len(config.Models))
+		for n := range config.Models {
+			modelNames = append(modelNames, n)
+		}
+		sort.Strings(modelNames)
+		return nil, &controllerConfigError{
+			Kind: ErrNoControllers,
+			Msg:  fmt.Sprintf("model %q not found (available: %s)", modelName, strings.Join(modelNames, ", ")),
+		}
 	}
 
-	// convert to the map and extract the only entry
-	controllerConfig := controllerConfig{}
-	for _, v := range cliOutput.(map[string]interface{}) {
-		// now v is a map[string]interface{} type
-		marshalled, err := json.Marshal(v)
+	return result, nil
+}
+
+// JWTConfig carries the parameters needed to authenticate to a
+// JIMM-fronted controller (or a controller configured to trust an
+// external JWKS) using a JWT instead of a username/password.
+//
+// Exactly one credential source should be set: JWT (a token supplied
+// as-is), JWTFile (a path to one), or TokenURL/ClientID/ClientSecret to
+// fetch one via the OAuth2 client-credentials flow against the IdP's
+// token endpoint. JWKSURL is unrelated to token fetching: it is the
+// JWKS endpoint the controller/JIMM itself uses to validate the JWT's
+// signature, and is only ever forwarded as JUJU_JWKS_URL.
+type JWTConfig struct {
+	JWT          string
+	JWTFile      string
+	JWKSURL      string
+	TokenURL     string
+	ClientID     string
+	ClientSecret string
+}
+
+// GetJWTControllerConfig resolves the controller addresses and CA
+// certificate the same way GetLocalControllerConfig does, but replaces
+// the username/password credentials with a JWT, for use against a
+// JIMM-fronted controller. Callers are expected to wire JUJU_JWT into
+// the Juju API client's login params instead of JUJU_USERNAME/
+// JUJU_PASSWORD.
+func GetJWTControllerConfig(controllerName string, modelName string, jwtConfig JWTConfig) (map[string]string, error) {
+	config, err := GetLocalControllerConfig(controllerName, modelName)
+	if err != nil {
+		return nil, err
+	}
+
+	token, err := resolveJWT(jwtConfig)
+	if err != nil {
+		return nil, err
+	}
+
+	delete(config, "JUJU_USERNAME")
+	delete(config, "JUJU_PASSWORD")
+	config["JUJU_JWT"] = token
+	if jwtConfig.JWKSURL != "" {
+		config["JUJU_JWKS_URL"] = jwtConfig.JWKSURL
+	}
+
+	return config, nil
+}
+
+// resolveJWT returns the JWT described by jwtConfig, fetching one via
+// the OAuth2 client-credentials flow if no token was supplied directly.
+func resolveJWT(jwtConfig JWTConfig) (string, error) {
+	if jwtConfig.JWT != "" {
+		return jwtConfig.JWT, nil
+	}
+
+	if jwtConfig.JWTFile != "" {
+		data, err := os.ReadFile(jwtConfig.JWTFile)
+		if err != nil {
+			return "", fmt.Errorf("error reading JWT file: %w", err)
+		}
+		return strings.TrimSpace(string(data)), nil
+	}
+
+	if jwtConfig.TokenURL != "" && jwtConfig.ClientID != "" && jwtConfig.ClientSecret != "" {
+		oauthConfig := clientcredentials.Config{
+			ClientID:     jwtConfig.ClientID,
+			ClientSecret: jwtConfig.ClientSecret,
+			TokenURL:     jwtConfig.TokenURL,
+		}
+
+		token, err := oauthConfig.Token(context.Background())
 		if err != nil {
-			log.Error().Err(err).Msg("error marshalling provider config")
-			return
+			return "", fmt.Errorf("error fetching JWT from token endpoint: %w", err)
 		}
-		// now we have a controllerConfig type
-		err = json.Unmarshal(marshalled, &controllerConfig)
+
+		return token.AccessToken, nil
+	}
+
+	return "", errors.New("no JWT credential source configured: set jwt, jwt_file, or token_url/client_id/client_secret")
+}
+
+// serverFileConfig is the YAML layout consumed by `juju login --server
+// <file>`.
+type serverFileConfig struct {
+	Addresses      []string `yaml:"addresses"`
+	CACert         string   `yaml:"ca-cert"`
+	Username       string   `yaml:"username"`
+	Password       string   `yaml:"password"`
+	ControllerUUID string   `yaml:"controller-uuid"`
+}
+
+// GetControllerConfigFromServerFile parses a Juju "server file" - the
+// same YAML layout `juju login --server <file>` consumes - into the same
+// shape returned by GetLocalControllerConfig. serverFile may be a path
+// to such a file, or its contents supplied directly (e.g. from a
+// Terraform heredoc). This lets ops teams hand Terraform runners a
+// single artifact with controller credentials, without requiring the
+// juju CLI or the full client store to be present.
+//
+// GetControllerConfig uses this (through populateControllerConfig),
+// ahead of the client store/CLI probe, when passed a non-empty
+// serverFile.
+func GetControllerConfigFromServerFile(serverFile string) (map[string]string, error) {
+	data := []byte(serverFile)
+	if _, err := os.Stat(serverFile); err == nil {
+		data, err = os.ReadFile(serverFile)
 		if err != nil {
-			log.Error().Err(err).Msg("error unmarshalling provider configuration from Juju CLI")
-			return
+			return nil, fmt.Errorf("error reading server file: %w", err)
 		}
-		break
 	}
 
-	localProviderConfig = map[string]string{}
-	localProviderConfig["JUJU_CONTROLLER_ADDRESSES"] = strings.Join(controllerConfig.ProviderDetails.ApiEndpoints, ",")
-	localProviderConfig["JUJU_CA_CERT"] = controllerConfig.ProviderDetails.CACert
-	localProviderConfig["JUJU_USERNAME"] = controllerConfig.Account.User
-	localProviderConfig["JUJU_PASSWORD"] = controllerConfig.Account.Password
+	var config serverFileConfig
+	if err := yaml.Unmarshal(data, &config); err != nil {
+		return nil, fmt.Errorf("error unmarshalling server file: %w", err)
+	}
 
-	log.Debug().Str("localProviderConfig", fmt.Sprintf("%#v", localProviderConfig)).Msg("local provider config was set")
-}
\ No newline at end of file
+	if len(config.Addresses) == 0 {
+		return nil, errors.New("server file does not contain any controller addresses")
+	}
+
+	return map[string]string{
+		"JUJU_CONTROLLER_ADDRESSES": strings.Join(config.Addresses, ","),
+		"JUJU_CA_CERT":              config.CACert,
+		"JUJU_USERNAME":             config.Username,
+		"JUJU_PASSWORD":             config.Password,
+	}, nil
+}
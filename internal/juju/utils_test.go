@@ -0,0 +1,284 @@
+package juju
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func writeClientStore(t *testing.T, dir string, controllers, accounts, models string) {
+	t.Helper()
+
+	if controllers != "" {
+		if err := os.WriteFile(filepath.Join(dir, "controllers.yaml"), []byte(controllers), 0o600); err != nil {
+			t.Fatalf("writing controllers.yaml: %v", err)
+		}
+	}
+	if accounts != "" {
+		if err := os.WriteFile(filepath.Join(dir, "accounts.yaml"), []byte(accounts), 0o600); err != nil {
+			t.Fatalf("writing accounts.yaml: %v", err)
+		}
+	}
+	if models != "" {
+		if err := os.WriteFile(filepath.Join(dir, "models.yaml"), []byte(models), 0o600); err != nil {
+			t.Fatalf("writing models.yaml: %v", err)
+		}
+	}
+}
+
+const testControllersYAML = `
+current-controller: mycontroller
+controllers:
+  mycontroller:
+    api-endpoints: ["10.0.0.1:17070"]
+    ca-cert: mycontroller-ca-cert
+  othercontroller:
+    api-endpoints: ["10.0.0.2:17070"]
+    ca-cert: othercontroller-ca-cert
+`
+
+const testAccountsYAML = `
+controllers:
+  mycontroller:
+    user: admin
+    password: mycontroller-pw
+  othercontroller:
+    user: admin
+    password: othercontroller-pw
+`
+
+const testModelsYAML = `
+controllers:
+  mycontroller:
+    current-model: admin/default
+    models:
+      admin/default:
+        uuid: model-uuid-default
+      admin/other:
+        uuid: model-uuid-other
+`
+
+func TestControllerConfigFromClientStore_CurrentControllerAndModel(t *testing.T) {
+	dir := t.TempDir()
+	writeClientStore(t, dir, testControllersYAML, testAccountsYAML, testModelsYAML)
+
+	config, err := controllerConfigFromClientStore(dir, "", "")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	want := map[string]string{
+		"JUJU_CONTROLLER_ADDRESSES": "10.0.0.1:17070",
+		"JUJU_CA_CERT":              "mycontroller-ca-cert",
+		"JUJU_USERNAME":             "admin",
+		"JUJU_PASSWORD":             "mycontroller-pw",
+		"JUJU_MODEL_UUID":           "model-uuid-default",
+	}
+	for k, v := range want {
+		if config[k] != v {
+			t.Errorf("config[%q] = %q, want %q", k, config[k], v)
+		}
+	}
+}
+
+func TestControllerConfigFromClientStore_NamedControllerAndModel(t *testing.T) {
+	dir := t.TempDir()
+	writeClientStore(t, dir, testControllersYAML, testAccountsYAML, testModelsYAML)
+
+	config, err := controllerConfigFromClientStore(dir, "mycontroller", "admin/other")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if config["JUJU_MODEL_UUID"] != "model-uuid-other" {
+		t.Errorf("JUJU_MODEL_UUID = %q, want model-uuid-other", config["JUJU_MODEL_UUID"])
+	}
+	if config["JUJU_PASSWORD"] != "mycontroller-pw" {
+		t.Errorf("JUJU_PASSWORD = %q, want mycontroller-pw", config["JUJU_PASSWORD"])
+	}
+}
+
+func TestControllerConfigFromClientStore_UnknownModel(t *testing.T) {
+	dir := t.TempDir()
+	writeClientStore(t, dir, testControllersYAML, testAccountsYAML, testModelsYAML)
+
+	_, err := controllerConfigFromClientStore(dir, "mycontroller", "admin/nosuchmodel")
+
+	var cfgErr *controllerConfigError
+	if !errors.As(err, &cfgErr) || cfgErr.Kind != ErrNoControllers {
+		t.Fatalf("err = %v, want a controllerConfigError with Kind ErrNoControllers", err)
+	}
+}
+
+func TestControllerConfigFromClientStore_UnknownController(t *testing.T) {
+	dir := t.TempDir()
+	writeClientStore(t, dir, testControllersYAML, testAccountsYAML, testModelsYAML)
+
+	_, err := controllerConfigFromClientStore(dir, "nosuchcontroller", "")
+
+	var cfgErr *controllerConfigError
+	if !errors.As(err, &cfgErr) || cfgErr.Kind != ErrNoControllers {
+		t.Fatalf("err = %v, want a controllerConfigError with Kind ErrNoControllers", err)
+	}
+}
+
+func TestControllerConfigFromClientStore_StoreUnavailable(t *testing.T) {
+	dir := t.TempDir()
+
+	_, err := controllerConfigFromClientStore(dir, "", "")
+
+	var cfgErr *controllerConfigError
+	if !errors.As(err, &cfgErr) || cfgErr.Kind != ErrStoreUnavailable {
+		t.Fatalf("err = %v, want a controllerConfigError with Kind ErrStoreUnavailable", err)
+	}
+}
+
+func TestGetLocalControllerConfig_CachesAndRespectsTTL(t *testing.T) {
+	dir := t.TempDir()
+	writeClientStore(t, dir, testControllersYAML, testAccountsYAML, testModelsYAML)
+
+	t.Setenv("JUJU_DATA", dir)
+	RefreshLocalControllerConfig()
+	t.Cleanup(RefreshLocalControllerConfig)
+
+	config, err := GetLocalControllerConfig("", "")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if config["JUJU_PASSWORD"] != "mycontroller-pw" {
+		t.Fatalf("JUJU_PASSWORD = %q, want mycontroller-pw", config["JUJU_PASSWORD"])
+	}
+
+	// Mutating the returned map must not corrupt the cached entry.
+	config["JUJU_PASSWORD"] = "tampered"
+	again, err := GetLocalControllerConfig("", "")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if again["JUJU_PASSWORD"] != "mycontroller-pw" {
+		t.Fatalf("cache was corrupted by caller mutation: JUJU_PASSWORD = %q", again["JUJU_PASSWORD"])
+	}
+
+	// Changing the store on disk is not picked up before the TTL expires.
+	writeClientStore(t, dir, `
+current-controller: mycontroller
+controllers:
+  mycontroller:
+    api-endpoints: ["10.0.0.9:17070"]
+    ca-cert: changed-ca-cert
+`, testAccountsYAML, "")
+	stale, err := GetLocalControllerConfig("", "")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if stale["JUJU_CA_CERT"] != "mycontroller-ca-cert" {
+		t.Fatalf("expected cached (stale) JUJU_CA_CERT, got %q", stale["JUJU_CA_CERT"])
+	}
+
+	// Force the cached entry to look expired, and the new value is picked up.
+	cache.mu.Lock()
+	cache.fetchedAt = time.Now().Add(-2 * controllerConfigTTL)
+	cache.mu.Unlock()
+
+	refreshed, err := GetLocalControllerConfig("", "")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if refreshed["JUJU_CA_CERT"] != "changed-ca-cert" {
+		t.Fatalf("expected refreshed JUJU_CA_CERT after TTL expiry, got %q", refreshed["JUJU_CA_CERT"])
+	}
+}
+
+func TestResolveJWT_DirectToken(t *testing.T) {
+	token, err := resolveJWT(JWTConfig{JWT: "a-token"})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if token != "a-token" {
+		t.Fatalf("token = %q, want a-token", token)
+	}
+}
+
+func TestResolveJWT_FromFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "token")
+	if err := os.WriteFile(path, []byte("a-token\n"), 0o600); err != nil {
+		t.Fatalf("writing token file: %v", err)
+	}
+
+	token, err := resolveJWT(JWTConfig{JWTFile: path})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if token != "a-token" {
+		t.Fatalf("token = %q, want a-token", token)
+	}
+}
+
+func TestResolveJWT_NoSourceConfigured(t *testing.T) {
+	if _, err := resolveJWT(JWTConfig{}); err == nil {
+		t.Fatal("expected an error when no JWT credential source is configured")
+	}
+}
+
+func TestGetControllerConfigFromServerFile_Inline(t *testing.T) {
+	yaml := `
+addresses: ["10.0.0.1:17070"]
+ca-cert: server-file-ca-cert
+username: admin
+password: server-file-pw
+`
+	config, err := GetControllerConfigFromServerFile(yaml)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if config["JUJU_CONTROLLER_ADDRESSES"] != "10.0.0.1:17070" {
+		t.Errorf("JUJU_CONTROLLER_ADDRESSES = %q, want 10.0.0.1:17070", config["JUJU_CONTROLLER_ADDRESSES"])
+	}
+	if config["JUJU_PASSWORD"] != "server-file-pw" {
+		t.Errorf("JUJU_PASSWORD = %q, want server-file-pw", config["JUJU_PASSWORD"])
+	}
+}
+
+func TestGetControllerConfigFromServerFile_Path(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "server-file.yaml")
+	contents := "addresses: [\"10.0.0.2:17070\"]\nusername: admin\npassword: pw\n"
+	if err := os.WriteFile(path, []byte(contents), 0o600); err != nil {
+		t.Fatalf("writing server file: %v", err)
+	}
+
+	config, err := GetControllerConfigFromServerFile(path)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if config["JUJU_CONTROLLER_ADDRESSES"] != "10.0.0.2:17070" {
+		t.Errorf("JUJU_CONTROLLER_ADDRESSES = %q, want 10.0.0.2:17070", config["JUJU_CONTROLLER_ADDRESSES"])
+	}
+}
+
+func TestGetControllerConfigFromServerFile_MissingAddresses(t *testing.T) {
+	_, err := GetControllerConfigFromServerFile("username: admin\npassword: pw\n")
+	if err == nil {
+		t.Fatal("expected an error when the server file has no controller addresses")
+	}
+}
+
+func TestPopulateControllerConfig_ServerFileTakesPrecedence(t *testing.T) {
+	// An empty, nonexistent data dir would fail both the client-store read
+	// and the CLI probe, so a successful result here proves the server
+	// file short-circuited both.
+	t.Setenv("JUJU_DATA", filepath.Join(t.TempDir(), "nonexistent"))
+	serverFile := `
+addresses: ["10.0.0.3:17070"]
+username: admin
+password: server-file-pw
+`
+	config, err := populateControllerConfig("", "", serverFile)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if config["JUJU_PASSWORD"] != "server-file-pw" {
+		t.Fatalf("JUJU_PASSWORD = %q, want server-file-pw", config["JUJU_PASSWORD"])
+	}
+}